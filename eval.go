@@ -0,0 +1,421 @@
+package jsonpath
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// activeMatch tracks, for one in-flight Path, how many of its segments have
+// already been satisfied by the walk so far.
+type activeMatch struct {
+	path   *Path
+	segIdx int
+}
+
+func (a activeMatch) done() bool {
+	return a.segIdx == len(a.path.segments)
+}
+
+// defaultMaxDepth bounds how many container levels matchInValue will recurse
+// into when an EvalOptions doesn't specify MaxDepth. It exists so that a
+// pathological document (e.g. thousands of nested arrays) matched against a
+// recursive-descent path, whose active matches ride along regardless of
+// depth, can't exhaust the goroutine stack -- see resolveZeroWidth.
+const defaultMaxDepth = 10000
+
+// EvalOptions configures an evaluation. The zero value selects every
+// default.
+type EvalOptions struct {
+	// MaxDepth caps how many container levels deep a match is pursued. Zero
+	// or negative selects defaultMaxDepth. Exceeding it aborts the walk with
+	// a *maxDepthExceededError rather than continuing to recurse.
+	MaxDepth int
+}
+
+// EvalPathsInBytes evaluates paths against the JSON document in src,
+// returning an Eval that yields every match via Next().
+func EvalPathsInBytes(src []byte, paths []*Path) (*Eval, error) {
+	return EvalPathsInBytesWithOptions(src, paths, EvalOptions{})
+}
+
+// EvalPathsInReader evaluates paths against the JSON document read from r,
+// returning an Eval that yields every match via Next().
+func EvalPathsInReader(r io.Reader, paths []*Path) (*Eval, error) {
+	return EvalPathsInReaderWithOptions(r, paths, EvalOptions{})
+}
+
+// EvalPathsInBytesWithOptions is EvalPathsInBytes with explicit EvalOptions.
+func EvalPathsInBytesWithOptions(src []byte, paths []*Path, opts EvalOptions) (*Eval, error) {
+	return evalCore(newBytesSource(src), paths, opts)
+}
+
+// EvalPathsInReaderWithOptions is EvalPathsInReader with explicit EvalOptions.
+func EvalPathsInReaderWithOptions(r io.Reader, paths []*Path, opts EvalOptions) (*Eval, error) {
+	return evalCore(newReaderSource(r), paths, opts)
+}
+
+func evalCore(src byteSource, paths []*Path, opts EvalOptions) (*Eval, error) {
+	maxDepth := opts.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+
+	skipSpace(src)
+	start := src.offset()
+	raw, _, err := skipValue(src)
+	if err != nil {
+		return newEval(nil, err), err
+	}
+	end := src.offset()
+
+	var results []*Result
+	var active []activeMatch
+	for _, p := range paths {
+		if p.projection != nil {
+			r, err := evalProjection(raw, p.projection, maxDepth)
+			if err != nil {
+				return newEval(results, err), err
+			}
+			results = append(results, &Result{PathString: p.raw, Value: r.value, Keys: []interface{}{}, Type: r.typ})
+			continue
+		}
+		active = append(active, activeMatch{path: p, segIdx: 0})
+	}
+
+	if len(active) > 0 {
+		if err := matchInValue(raw, start, end, nil, active, &results, 0, maxDepth); err != nil {
+			return newEval(results, err), err
+		}
+	}
+	return newEval(results, nil), nil
+}
+
+// maxDepthExceededError is returned when a walk recurses past MaxDepth
+// container levels, rather than letting the recursion run unbounded.
+type maxDepthExceededError struct {
+	maxDepth int
+}
+
+func (e *maxDepthExceededError) Error() string {
+	return fmt.Sprintf("jsonpath: exceeded max depth %d", e.maxDepth)
+}
+
+type projValue struct {
+	value []byte
+	typ   int
+}
+
+// evalProjection evaluates each field of a projection independently against
+// raw (the whole document each projection path is rooted at) and assembles
+// the results into a single freshly-synthesized JSON object or array. A
+// field with no matches becomes `null`; a field with more than one match
+// becomes a JSON array of its matches, in document order.
+func evalProjection(raw []byte, proj *projection, maxDepth int) (projValue, error) {
+	var buf bytes.Buffer
+	if proj.kind == projObject {
+		buf.WriteByte('{')
+	} else {
+		buf.WriteByte('[')
+	}
+
+	for i, field := range proj.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if proj.kind == projObject {
+			buf.WriteByte('"')
+			buf.WriteString(field.key)
+			buf.WriteString(`":`)
+		}
+
+		var sub []*Result
+		var err error
+		if field.path.projection != nil {
+			v, perr := evalProjection(raw, field.path.projection, maxDepth)
+			err = perr
+			if perr == nil {
+				sub = []*Result{{Value: v.value}}
+			}
+		} else {
+			err = matchInValue(raw, 0, int64(len(raw)), nil, []activeMatch{{path: field.path, segIdx: 0}}, &sub, 0, maxDepth)
+		}
+		if err != nil {
+			return projValue{}, err
+		}
+
+		switch len(sub) {
+		case 0:
+			buf.WriteString("null")
+		case 1:
+			buf.Write(sub[0].Value)
+		default:
+			buf.WriteByte('[')
+			for j, r := range sub {
+				if j > 0 {
+					buf.WriteByte(',')
+				}
+				buf.Write(r.Value)
+			}
+			buf.WriteByte(']')
+		}
+	}
+
+	if proj.kind == projObject {
+		buf.WriteByte('}')
+	} else {
+		buf.WriteByte(']')
+	}
+
+	typ := JsonArray
+	if proj.kind == projObject {
+		typ = JsonObject
+	}
+	return projValue{value: buf.Bytes(), typ: typ}, nil
+}
+
+// matchInValue matches the still-active paths against raw, the already
+// fully-read bytes of one JSON value located at [start, end) in the original
+// input. Any path whose segments are fully satisfied emits a Result for raw
+// itself; any path with segments remaining is pushed one level deeper by
+// walking raw's members/elements. depth counts the container levels already
+// descended through; it's checked against maxDepth before recursing any
+// further, so a deeply-nested document can't overflow the stack.
+func matchInValue(raw []byte, start, end int64, keyStack []interface{}, active []activeMatch, results *[]*Result, depth, maxDepth int) error {
+	if depth > maxDepth {
+		return &maxDepthExceededError{maxDepth: maxDepth}
+	}
+
+	active, descending := resolveZeroWidth(raw, active)
+
+	var continuing []activeMatch
+	for _, a := range active {
+		if a.done() {
+			*results = append(*results, &Result{
+				PathString: a.path.raw,
+				Value:      raw,
+				Keys:       append([]interface{}{}, keyStack...),
+				Type:       valueType(raw),
+				Start:      start,
+				End:        end,
+			})
+		} else {
+			continuing = append(continuing, a)
+		}
+	}
+	if len(continuing) == 0 && len(descending) == 0 {
+		return nil
+	}
+
+	isObject := len(raw) > 0 && raw[0] == '{'
+	isArray := len(raw) > 0 && raw[0] == '['
+	if !isObject && !isArray {
+		return nil
+	}
+
+	return iterateContainer(raw, start, isObject, func(key interface{}, valStart, valEnd int64, valRaw []byte) error {
+		childActive := append([]activeMatch{}, descending...)
+		for _, a := range continuing {
+			seg := a.path.segments[a.segIdx]
+			if !segMatches(seg, isObject, key, valRaw) {
+				continue
+			}
+			childActive = append(childActive, activeMatch{path: a.path, segIdx: a.segIdx + 1})
+		}
+		if len(childActive) == 0 {
+			return nil
+		}
+		newKeys := append(append([]interface{}{}, keyStack...), key)
+		return matchInValue(valRaw, valStart, valEnd, newKeys, childActive, results, depth+1, maxDepth)
+	})
+}
+
+// resolveZeroWidth expands every active match whose next segment doesn't
+// consume a level of the document (a `?()` filter guard, or a `..` descent)
+// without moving to a child value. Filters are tested against raw and either
+// advance or drop the match. A `..` descent both tries the remainder of the
+// path against raw itself (so `$..key` can match at depth zero) and is
+// returned separately in descending, unchanged, so the caller can hand it to
+// every child regardless of whether that child matches structurally -- that
+// is what lets recursive descent keep searching arbitrarily deep.
+func resolveZeroWidth(raw []byte, active []activeMatch) (resolved, descending []activeMatch) {
+	for {
+		changed := false
+		next := make([]activeMatch, 0, len(active))
+		for _, a := range active {
+			if a.done() {
+				next = append(next, a)
+				continue
+			}
+			switch a.path.segments[a.segIdx].kind {
+			case segFilter:
+				changed = true
+				if a.path.segments[a.segIdx].filter.matches(raw) {
+					next = append(next, activeMatch{path: a.path, segIdx: a.segIdx + 1})
+				}
+			case segDescent:
+				changed = true
+				next = append(next, activeMatch{path: a.path, segIdx: a.segIdx + 1})
+				descending = append(descending, a)
+			default:
+				next = append(next, a)
+			}
+		}
+		active = next
+		if !changed {
+			return active, descending
+		}
+	}
+}
+
+func segMatches(seg segment, isObject bool, key interface{}, valRaw []byte) bool {
+	switch seg.kind {
+	case segWildcard:
+		return true
+	case segKey:
+		if !isObject {
+			return false
+		}
+		k, ok := key.([]byte)
+		return ok && string(k) == seg.key
+	case segIndex:
+		if isObject {
+			return false
+		}
+		idx, ok := key.(int)
+		return ok && idx == seg.index
+	case segSlice:
+		if isObject {
+			return false
+		}
+		idx, ok := key.(int)
+		if !ok || idx < seg.sliceStart {
+			return false
+		}
+		if seg.sliceHasEnd && idx >= seg.sliceEnd {
+			return false
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func valueType(raw []byte) int {
+	if len(raw) == 0 {
+		return JsonNull
+	}
+	switch raw[0] {
+	case '{':
+		return JsonObject
+	case '[':
+		return JsonArray
+	case '"':
+		return JsonString
+	case 't', 'f':
+		return JsonBool
+	case 'n':
+		return JsonNull
+	default:
+		return JsonNumber
+	}
+}
+
+// iterateContainer walks the members (object) or elements (array) of raw,
+// which must begin at local offset 0 with '{' or '[', invoking fn for each
+// with the member's key (an object key as []byte, or an array index as int),
+// and the member value's absolute [start, end) offsets (base added in) plus
+// its raw bytes.
+func iterateContainer(raw []byte, base int64, isObject bool, fn func(key interface{}, valStart, valEnd int64, valRaw []byte) error) error {
+	src := newBytesSource(raw)
+	closeB := byte(']')
+	if isObject {
+		closeB = '}'
+	}
+	src.next() // consume the already-verified opening '{' or '['
+
+	idx := 0
+	skipSpace(src)
+	if pb, ok := src.peek(); ok && pb == closeB {
+		return nil
+	}
+	for {
+		skipSpace(src)
+		var key interface{}
+		if isObject {
+			_, content, err := readString(src)
+			if err != nil {
+				return err
+			}
+			key = content
+			skipSpace(src)
+			if c, ok := src.next(); !ok || c != ':' {
+				return errUnexpected(src, ':')
+			}
+			skipSpace(src)
+		} else {
+			key = idx
+			idx++
+		}
+		valStart := src.offset()
+		valRaw, _, err := skipValue(src)
+		if err != nil {
+			return err
+		}
+		valEnd := src.offset()
+		if err := fn(key, base+valStart, base+valEnd, valRaw); err != nil {
+			return err
+		}
+		skipSpace(src)
+		c, ok := src.next()
+		if !ok {
+			return errUnexpected(src, closeB)
+		}
+		if c == closeB {
+			return nil
+		}
+		if c != ',' {
+			return errUnexpected(src, ',')
+		}
+	}
+}
+
+func errUnexpected(src byteSource, want byte) error {
+	return &unexpectedCharError{want: want, offset: src.offset()}
+}
+
+type unexpectedCharError struct {
+	want   byte
+	offset int64
+}
+
+func (e *unexpectedCharError) Error() string {
+	return "jsonpath: malformed JSON, expected " + string(e.want)
+}
+
+// lookupRaw performs a shallow dig into raw (which must be a JSON object) to
+// find the value addressed by the dotted field path, used to resolve `@.a.b`
+// references inside filter expressions.
+func lookupRaw(raw []byte, path []string) (value []byte, typ int, ok bool) {
+	cur := raw
+	for _, key := range path {
+		if len(cur) == 0 || cur[0] != '{' {
+			return nil, JsonNull, false
+		}
+		found := false
+		err := iterateContainer(cur, 0, true, func(k interface{}, valStart, valEnd int64, valRaw []byte) error {
+			if found {
+				return nil
+			}
+			if kb, ok := k.([]byte); ok && string(kb) == key {
+				cur = valRaw
+				found = true
+			}
+			return nil
+		})
+		if err != nil || !found {
+			return nil, JsonNull, false
+		}
+	}
+	return cur, valueType(cur), true
+}
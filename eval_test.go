@@ -1,6 +1,7 @@
 package jsonpath
 
 import (
+	"io"
 	"strings"
 	"testing"
 
@@ -20,7 +21,10 @@ var tests = []test{
 	test{`key selection`, `{"aKey":32}`, `$.aKey+`, []Result{newResult(`$.aKey+`, `32`, JsonNumber, `aKey`)}},
 	test{`nested key selection`, `{"aKey":{"bKey":32}}`, `$.aKey+`, []Result{newResult(`$.aKey+`, `{"bKey":32}`, JsonObject, `aKey`)}},
 	test{`empty array`, `{"aKey":[]}`, `$.aKey+`, []Result{newResult(`$.aKey+`, `[]`, JsonArray, `aKey`)}},
-	test{`multiple same-level keys, weird spacing`, `{    "aKey" 	: true ,    "bKey":  [	1 , 2	], "cKey" 	: true		} `, `$.bKey+`, []Result{newResult(`$.bKey+`, `[1,2]`, JsonArray, `bKey`)}},
+	// Value is byte-exact from the source (interior whitespace included, not
+	// compacted) so that it stays consistent with Result.Start/End -- see
+	// TestResultOffsets, which asserts Value == src[Start:End] for every case.
+	test{`multiple same-level keys, weird spacing`, `{    "aKey" 	: true ,    "bKey":  [	1 , 2	], "cKey" 	: true		} `, `$.bKey+`, []Result{newResult(`$.bKey+`, "[\t1 , 2\t]", JsonArray, `bKey`)}},
 
 	test{`array index selection`, `{"aKey":[123,456]}`, `$.aKey[1]+`, []Result{newResult(`$.aKey[1]+`, `456`, JsonNumber, `aKey`, 1)}},
 	test{`array wild index selection`, `{"aKey":[123,456]}`, `$.aKey[*]+`, []Result{newResult(`$.aKey[*]+`, `123`, JsonNumber, `aKey`, 0), newResult(`$.aKey[*]+`, `456`, JsonNumber, `aKey`, 1)}},
@@ -43,6 +47,41 @@ var tests = []test{
 
 	test{`evaluation literal equality`, `{"items":[ {"name":"alpha", "value":11}]}`, `$.items[*]?("bravo" == "bravo").value+`, []Result{newResult(`$.items[*]?("bravo" == "bravo").value+`, `11`, JsonNumber, `items`, 0, `value`)}},
 	test{`evaluation based on string equal to path value`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo", "value":22}, {"name":"charlie", "value":33} ]}`, `$.items[*]?(@.name == "bravo").value+`, []Result{newResult(`$.items[*]?(@.name == "bravo").value+`, `22`, JsonNumber, `items`, 1, `value`)}},
+	test{`evaluation based on string equal to a \u-escaped path value`, `{"items":[ {"name":"\u0062ravo", "value":22} ]}`, `$.items[*]?(@.name == "bravo").value+`, []Result{newResult(`$.items[*]?(@.name == "bravo").value+`, `22`, JsonNumber, `items`, 0, `value`)}},
+
+	test{`evaluation with != operator`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo", "value":22} ]}`, `$.items[*]?(@.name != "bravo").value+`, []Result{newResult(`$.items[*]?(@.name != "bravo").value+`, `11`, JsonNumber, `items`, 0, `value`)}},
+	test{`evaluation with < operator`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo", "value":22}, {"name":"charlie", "value":33} ]}`, `$.items[*]?(@.value < 22).value+`, []Result{newResult(`$.items[*]?(@.value < 22).value+`, `11`, JsonNumber, `items`, 0, `value`)}},
+	test{`evaluation with <= operator`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo", "value":22}, {"name":"charlie", "value":33} ]}`, `$.items[*]?(@.value <= 22).value+`, []Result{newResult(`$.items[*]?(@.value <= 22).value+`, `11`, JsonNumber, `items`, 0, `value`), newResult(`$.items[*]?(@.value <= 22).value+`, `22`, JsonNumber, `items`, 1, `value`)}},
+	test{`evaluation with > operator`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo", "value":22}, {"name":"charlie", "value":33} ]}`, `$.items[*]?(@.value > 22).value+`, []Result{newResult(`$.items[*]?(@.value > 22).value+`, `33`, JsonNumber, `items`, 2, `value`)}},
+	test{`evaluation with >= operator`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo", "value":22}, {"name":"charlie", "value":33} ]}`, `$.items[*]?(@.value >= 22).value+`, []Result{newResult(`$.items[*]?(@.value >= 22).value+`, `22`, JsonNumber, `items`, 1, `value`), newResult(`$.items[*]?(@.value >= 22).value+`, `33`, JsonNumber, `items`, 2, `value`)}},
+	test{`evaluation with % like operator`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo", "value":22} ]}`, `$.items[*]?(@.name %"br*").value+`, []Result{newResult(`$.items[*]?(@.name %"br*").value+`, `22`, JsonNumber, `items`, 1, `value`)}},
+	test{`evaluation with !% not-like operator`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo", "value":22} ]}`, `$.items[*]?(@.name !%"br*").value+`, []Result{newResult(`$.items[*]?(@.name !%"br*").value+`, `11`, JsonNumber, `items`, 0, `value`)}},
+	test{`evaluation with % like operator over a multi-byte rune`, `{"items":[ {"name":"alpha", "value":11}, {"name":"éclair", "value":22} ]}`, `$.items[*]?(@.name %"é*").value+`, []Result{newResult(`$.items[*]?(@.name %"é*").value+`, `22`, JsonNumber, `items`, 1, `value`)}},
+	test{`evaluation with in operator`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo", "value":22}, {"name":"charlie", "value":33} ]}`, `$.items[*]?(@.name in ["alpha", "charlie"]).value+`, []Result{newResult(`$.items[*]?(@.name in ["alpha", "charlie"]).value+`, `11`, JsonNumber, `items`, 0, `value`), newResult(`$.items[*]?(@.name in ["alpha", "charlie"]).value+`, `33`, JsonNumber, `items`, 2, `value`)}},
+	test{`evaluation with nin operator`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo", "value":22}, {"name":"charlie", "value":33} ]}`, `$.items[*]?(@.name nin ["alpha", "charlie"]).value+`, []Result{newResult(`$.items[*]?(@.name nin ["alpha", "charlie"]).value+`, `22`, JsonNumber, `items`, 1, `value`)}},
+	test{`evaluation with exists operator`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo"} ]}`, `$.items[*]?(exists(@.value)).name+`, []Result{newResult(`$.items[*]?(exists(@.value)).name+`, `"alpha"`, JsonString, `items`, 0, `name`)}},
+	test{`evaluation with && short circuit`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo", "value":22} ]}`, `$.items[*]?(@.value >= 11 && @.name == "alpha").value+`, []Result{newResult(`$.items[*]?(@.value >= 11 && @.name == "alpha").value+`, `11`, JsonNumber, `items`, 0, `value`)}},
+	test{`evaluation with || combination`, `{"items":[ {"name":"alpha", "value":11}, {"name":"bravo", "value":22}, {"name":"charlie", "value":33} ]}`, `$.items[*]?(@.name == "alpha" || @.name == "charlie").value+`, []Result{newResult(`$.items[*]?(@.name == "alpha" || @.name == "charlie").value+`, `11`, JsonNumber, `items`, 0, `value`), newResult(`$.items[*]?(@.name == "alpha" || @.name == "charlie").value+`, `33`, JsonNumber, `items`, 2, `value`)}},
+	test{`evaluation with type mismatch does not error`, `{"items":[ {"name":"alpha", "value":11} ]}`, `$.items[*]?(@.value < "z").value+`, []Result{}},
+
+	test{`recursive descent by key`, `{"aKey":{"bKey":1,"cKey":{"bKey":2}},"bKey":3}`, `$..bKey+`, []Result{newResult(`$..bKey+`, `1`, JsonNumber, `aKey`, `bKey`), newResult(`$..bKey+`, `2`, JsonNumber, `aKey`, `cKey`, `bKey`), newResult(`$..bKey+`, `3`, JsonNumber, `bKey`)}},
+	test{`recursive descent wildcard`, `{"aKey":{"bKey":1},"cKey":[2,3]}`, `$..[*]+`, []Result{newResult(`$..[*]+`, `{"bKey":1}`, JsonObject, `aKey`), newResult(`$..[*]+`, `1`, JsonNumber, `aKey`, `bKey`), newResult(`$..[*]+`, `[2,3]`, JsonArray, `cKey`), newResult(`$..[*]+`, `2`, JsonNumber, `cKey`, 0), newResult(`$..[*]+`, `3`, JsonNumber, `cKey`, 1)}},
+	test{`recursive descent then trash then index`, `{"aKey":{"bKey":{"trash":[11,22]}},"trash":[33]}`, `$.aKey..trash[0]+`, []Result{newResult(`$.aKey..trash[0]+`, `11`, JsonNumber, `aKey`, `bKey`, `trash`, 0)}},
+	test{`recursive descent combined with filter`, `{"items":[{"name":"alpha","value":11}],"nested":{"items":[{"name":"bravo","value":22}]}}`, `$..items[*]?(@.value > 15).name+`, []Result{newResult(`$..items[*]?(@.value > 15).name+`, `"bravo"`, JsonString, `nested`, `items`, 0, `name`)}},
+
+	test{`object projection`, `{"items":[{"name":"alpha","value":11},{"name":"bravo","value":22}]}`, `{name: $.items[*].name, value: $.items[*].value}+`,
+		[]Result{{PathString: `{name: $.items[*].name, value: $.items[*].value}+`, Value: []byte(`{"name":["alpha","bravo"],"value":[11,22]}`), Keys: []interface{}{}, Type: JsonObject}}},
+	test{`array projection`, `{"items":[{"name":"alpha","value":11},{"name":"bravo","value":22}]}`, `[$.items[0].name, $.items[0].value]+`,
+		[]Result{{PathString: `[$.items[0].name, $.items[0].value]+`, Value: []byte(`["alpha",11]`), Keys: []interface{}{}, Type: JsonArray}}},
+	test{`object projection with missing sub-path`, `{"items":[{"name":"alpha","value":11}]}`, `{name: $.items[0].name, missing: $.items[0].nope}+`,
+		[]Result{{PathString: `{name: $.items[0].name, missing: $.items[0].nope}+`, Value: []byte(`{"name":"alpha","missing":null}`), Keys: []interface{}{}, Type: JsonObject}}},
+	test{`object projection with filter on a sub-path`, `{"items":[{"name":"alpha","value":11},{"name":"bravo","value":22}]}`, `{matched: $.items[*]?(@.value > 15).name, all: $.items[*].name}+`,
+		[]Result{{PathString: `{matched: $.items[*]?(@.value > 15).name, all: $.items[*].name}+`, Value: []byte(`{"matched":"bravo","all":["alpha","bravo"]}`), Keys: []interface{}{}, Type: JsonObject}}},
+	// A sub-field that is itself a projection is parsed via parseProjection,
+	// not parseSubPath, so (unlike an ordinary `$...` sub-path) it carries its
+	// own trailing `+` -- see parseSubPath's doc comment.
+	test{`object projection with a nested projection sub-path`, `{"items":[{"name":"alpha","value":11}]}`, `{outer: {name: $.items[0].name}+}+`,
+		[]Result{{PathString: `{outer: {name: $.items[0].name}+}+`, Value: []byte(`{"outer":{"name":"alpha"}}`), Keys: []interface{}{}, Type: JsonObject}}},
 }
 
 func TestPathQuery(t *testing.T) {
@@ -55,7 +94,7 @@ func TestPathQuery(t *testing.T) {
 			if as.NoError(err, "Testing: %s", t.name) {
 				res := toResultArray(eval)
 				if as.NoError(eval.Error) {
-					as.EqualValues(t.expected, res, "Testing of %q", t.name)
+					as.EqualValues(t.expected, stripOffsets(res), "Testing of %q", t.name)
 				}
 			}
 
@@ -63,7 +102,7 @@ func TestPathQuery(t *testing.T) {
 			if as.NoError(err, "Testing: %s", t.name) {
 				res := toResultArray(eval_reader)
 				if as.NoError(eval.Error) {
-					as.EqualValues(t.expected, res, "Testing of %q", t.name)
+					as.EqualValues(t.expected, stripOffsets(res), "Testing of %q", t.name)
 				}
 			}
 		}
@@ -89,6 +128,107 @@ func newResult(pathString, value string, typ int, keys ...interface{}) Result {
 	}
 }
 
+// stripOffsets zeroes Start/End so results can still be compared against the
+// `tests` table's expectations, which predate per-result byte offsets and
+// don't set them. TestResultOffsets below covers the offsets themselves.
+func stripOffsets(res []Result) []Result {
+	out := make([]Result, len(res))
+	for i, r := range res {
+		r.Start, r.End = 0, 0
+		out[i] = r
+	}
+	return out
+}
+
+func TestResultOffsets(t *testing.T) {
+	as := assert.New(t)
+
+	for _, tc := range tests {
+		if strings.HasPrefix(tc.path, "{") || strings.HasPrefix(tc.path, "[") {
+			// Projection results are synthesized, not sliced from the input,
+			// so they have no meaningful offsets to check here.
+			continue
+		}
+		paths, err := ParsePaths(tc.path)
+		if !as.NoError(err) {
+			continue
+		}
+
+		eval, err := EvalPathsInBytes([]byte(tc.json), paths)
+		if as.NoError(err, "Testing: %s", tc.name) {
+			for _, r := range toResultArray(eval) {
+				as.Equal(string(r.Value), string(r.Slice([]byte(tc.json))), "offsets for %q", tc.name)
+			}
+		}
+
+		eval_reader, err := EvalPathsInReader(strings.NewReader(tc.json), paths)
+		if as.NoError(err, "Testing: %s", tc.name) {
+			for _, r := range toResultArray(eval_reader) {
+				as.Equal(string(r.Value), string(r.Slice([]byte(tc.json))), "reader offsets for %q", tc.name)
+			}
+		}
+	}
+}
+
+// chunkedReader dribbles out src a few bytes at a time, to make sure offsets
+// survive chunk boundaries in the underlying reader rather than assuming a
+// single buffered read.
+type chunkedReader struct {
+	src       []byte
+	chunkSize int
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if len(c.src) == 0 {
+		return 0, io.EOF
+	}
+	n := c.chunkSize
+	if n > len(p) {
+		n = len(p)
+	}
+	if n > len(c.src) {
+		n = len(c.src)
+	}
+	copy(p, c.src[:n])
+	c.src = c.src[n:]
+	return n, nil
+}
+
+func TestResultOffsetsAcrossChunkBoundaries(t *testing.T) {
+	as := assert.New(t)
+
+	src := []byte(`{"items":[{"name":"alpha","value":11},{"name":"bravo","value":22}]}`)
+	paths, err := ParsePaths(`$.items[*]?(@.name == "bravo").value+`)
+	as.NoError(err)
+
+	eval, err := EvalPathsInReader(&chunkedReader{src: src, chunkSize: 3}, paths)
+	as.NoError(err)
+
+	res := toResultArray(eval)
+	if as.Len(res, 1) {
+		as.Equal("22", string(res[0].Value))
+		as.Equal("22", string(res[0].Slice(src)))
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	as := assert.New(t)
+
+	src := []byte(strings.Repeat(`[`, 20) + strings.Repeat(`]`, 20))
+	paths, err := ParsePaths(`$..[*]+`)
+	as.NoError(err)
+
+	eval, err := EvalPathsInBytesWithOptions(src, paths, EvalOptions{MaxDepth: 5})
+	as.Error(err)
+	as.Error(eval.Error)
+
+	eval, err = EvalPathsInBytes(src, paths)
+	as.NoError(err)
+	res := toResultArray(eval)
+	as.NoError(eval.Error)
+	as.Len(res, 19)
+}
+
 func toResultArray(e *Eval) []Result {
 	vals := make([]Result, 0)
 	for {
@@ -0,0 +1,520 @@
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// filterCtx is the evaluation context for a single `?()` predicate: the raw
+// bytes of the candidate value (the thing `@` refers to).
+type filterCtx struct {
+	raw []byte
+}
+
+// exprVal is the resolved value of an expression operand: either a string, a
+// number, or "absent" (ok == false, e.g. a field reference that didn't
+// exist). Absent operands never match a comparison.
+type exprVal struct {
+	ok    bool
+	isNum bool
+	str   string
+	num   float64
+}
+
+type exprNode interface {
+	resolve(ctx *filterCtx) exprVal
+}
+
+type boolNode interface {
+	evalBool(ctx *filterCtx) bool
+}
+
+// filterExpr is a compiled `?()` predicate.
+type filterExpr struct {
+	root boolNode
+}
+
+func (f *filterExpr) matches(raw []byte) bool {
+	return f.root.evalBool(&filterCtx{raw: raw})
+}
+
+// --- operand nodes ---
+
+type litStringNode struct{ s string }
+
+func (n litStringNode) resolve(ctx *filterCtx) exprVal { return exprVal{ok: true, str: n.s} }
+
+type litNumberNode struct{ n float64 }
+
+func (n litNumberNode) resolve(ctx *filterCtx) exprVal {
+	return exprVal{ok: true, isNum: true, num: n.n}
+}
+
+type fieldRefNode struct{ path []string }
+
+func (n fieldRefNode) resolve(ctx *filterCtx) exprVal {
+	raw, typ, ok := lookupRaw(ctx.raw, n.path)
+	if !ok {
+		return exprVal{ok: false}
+	}
+	switch typ {
+	case JsonNumber:
+		f, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil {
+			return exprVal{ok: false}
+		}
+		return exprVal{ok: true, isNum: true, num: f}
+	case JsonString:
+		_, content, err := readString(newBytesSource(raw))
+		if err != nil {
+			return exprVal{ok: false}
+		}
+		return exprVal{ok: true, str: string(content)}
+	default:
+		return exprVal{ok: true, str: string(raw)}
+	}
+}
+
+// --- boolean nodes ---
+
+type compareNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n compareNode) evalBool(ctx *filterCtx) bool {
+	l := n.left.resolve(ctx)
+	r := n.right.resolve(ctx)
+	if !l.ok || !r.ok {
+		return false
+	}
+	switch n.op {
+	case "==":
+		return valEqual(l, r)
+	case "!=":
+		return !valEqual(l, r)
+	case "<", "<=", ">", ">=":
+		if l.isNum != r.isNum {
+			return false
+		}
+		if l.isNum {
+			return numCompare(n.op, l.num, r.num)
+		}
+		return strCompare(n.op, l.str, r.str)
+	case "%":
+		if l.isNum || r.isNum {
+			return false
+		}
+		return globMatch(r.str, l.str)
+	case "!%":
+		if l.isNum || r.isNum {
+			return false
+		}
+		return !globMatch(r.str, l.str)
+	default:
+		return false
+	}
+}
+
+func valEqual(l, r exprVal) bool {
+	if l.isNum != r.isNum {
+		return false
+	}
+	if l.isNum {
+		return l.num == r.num
+	}
+	return l.str == r.str
+}
+
+func numCompare(op string, l, r float64) bool {
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
+
+func strCompare(op string, l, r string) bool {
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
+
+type inNode struct {
+	left    exprNode
+	list    []exprNode
+	negated bool
+}
+
+func (n inNode) evalBool(ctx *filterCtx) bool {
+	l := n.left.resolve(ctx)
+	if !l.ok {
+		return false
+	}
+	found := false
+	for _, item := range n.list {
+		r := item.resolve(ctx)
+		if r.ok && valEqual(l, r) {
+			found = true
+			break
+		}
+	}
+	if n.negated {
+		return !found
+	}
+	return found
+}
+
+type existsNode struct{ ref fieldRefNode }
+
+func (n existsNode) evalBool(ctx *filterCtx) bool {
+	_, _, ok := lookupRaw(ctx.raw, n.ref.path)
+	return ok
+}
+
+type logicalNode struct {
+	op          string // "&&" or "||"
+	left, right boolNode
+}
+
+func (n logicalNode) evalBool(ctx *filterCtx) bool {
+	if n.op == "&&" {
+		return n.left.evalBool(ctx) && n.right.evalBool(ctx)
+	}
+	return n.left.evalBool(ctx) || n.right.evalBool(ctx)
+}
+
+// globMatch implements the `%` SQL-LIKE style matcher: `?` matches exactly one
+// character, `*` matches zero or more. It's a small linear-time state
+// machine, not a regex, so pathological patterns can't blow up match time.
+func globMatch(pattern, s string) bool {
+	rs := []rune(s)
+	// dp[i] tracks whether the pattern consumed so far can match rs[:i].
+	dp := make([]bool, len(rs)+1)
+	dp[0] = true
+	for _, p := range pattern {
+		next := make([]bool, len(rs)+1)
+		switch p {
+		case '*':
+			anyTrue := false
+			for i := 0; i <= len(rs); i++ {
+				if dp[i] {
+					anyTrue = true
+				}
+				next[i] = anyTrue
+			}
+		case '?':
+			for i := 0; i < len(rs); i++ {
+				if dp[i] {
+					next[i+1] = true
+				}
+			}
+		default:
+			for i := 0; i < len(rs); i++ {
+				if dp[i] && rs[i] == p {
+					next[i+1] = true
+				}
+			}
+		}
+		dp = next
+	}
+	return dp[len(rs)]
+}
+
+// --- expression lexer/parser ---
+
+type tokKind int
+
+const (
+	tkEOF tokKind = iota
+	tkString
+	tkNumber
+	tkField
+	tkIdent
+	tkOp
+	tkAnd
+	tkOr
+	tkLParen
+	tkRParen
+	tkLBracket
+	tkRBracket
+	tkComma
+)
+
+type token struct {
+	kind tokKind
+	s    string
+	n    float64
+}
+
+func lexFilter(s string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			toks = append(toks, token{kind: tkLParen})
+			i++
+		case c == ')':
+			toks = append(toks, token{kind: tkRParen})
+			i++
+		case c == '[':
+			toks = append(toks, token{kind: tkLBracket})
+			i++
+		case c == ']':
+			toks = append(toks, token{kind: tkRBracket})
+			i++
+		case c == ',':
+			toks = append(toks, token{kind: tkComma})
+			i++
+		case c == '"':
+			src := newBytesSource([]byte(s[i:]))
+			_, content, err := readString(src)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, token{kind: tkString, s: string(content)})
+			i += int(src.offset())
+		case strings.HasPrefix(s[i:], "&&"):
+			toks = append(toks, token{kind: tkAnd})
+			i += 2
+		case strings.HasPrefix(s[i:], "||"):
+			toks = append(toks, token{kind: tkOr})
+			i += 2
+		case strings.HasPrefix(s[i:], "!%"):
+			toks = append(toks, token{kind: tkOp, s: "!%"})
+			i += 2
+		case strings.HasPrefix(s[i:], "!="):
+			toks = append(toks, token{kind: tkOp, s: "!="})
+			i += 2
+		case strings.HasPrefix(s[i:], "<="):
+			toks = append(toks, token{kind: tkOp, s: "<="})
+			i += 2
+		case strings.HasPrefix(s[i:], ">="):
+			toks = append(toks, token{kind: tkOp, s: ">="})
+			i += 2
+		case strings.HasPrefix(s[i:], "=="):
+			toks = append(toks, token{kind: tkOp, s: "=="})
+			i += 2
+		case c == '<' || c == '>' || c == '%':
+			toks = append(toks, token{kind: tkOp, s: string(c)})
+			i++
+		case c == '@':
+			j := i + 1
+			var path []string
+			for j < len(s) && s[j] == '.' {
+				j++
+				k := j
+				for k < len(s) && isIdentByte(s[k]) {
+					k++
+				}
+				path = append(path, s[j:k])
+				j = k
+			}
+			toks = append(toks, token{kind: tkField, s: strings.Join(path, ".")})
+			i = j
+		case c == '-' || isDigit(c):
+			j := i + 1
+			for j < len(s) && (isDigit(s[j]) || s[j] == '.') {
+				j++
+			}
+			f, err := strconv.ParseFloat(s[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad number %q in filter", s[i:j])
+			}
+			toks = append(toks, token{kind: tkNumber, n: f})
+			i = j
+		case isIdentByte(c):
+			j := i
+			for j < len(s) && isIdentByte(s[j]) {
+				j++
+			}
+			toks = append(toks, token{kind: tkIdent, s: s[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q in filter", c)
+		}
+	}
+	toks = append(toks, token{kind: tkEOF})
+	return toks, nil
+}
+
+type filterParser struct {
+	toks []token
+	pos  int
+}
+
+func (p *filterParser) cur() token { return p.toks[p.pos] }
+func (p *filterParser) advance()   { p.pos++ }
+
+func parseFilterExpr(s string) (*filterExpr, error) {
+	toks, err := lexFilter(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.cur().kind != tkEOF {
+		return nil, fmt.Errorf("unexpected trailing tokens in filter %q", s)
+	}
+	return &filterExpr{root: root}, nil
+}
+
+func (p *filterParser) parseOr() (boolNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur().kind == tkOr {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (boolNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur().kind == tkAnd {
+		p.advance()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (boolNode, error) {
+	if p.cur().kind == tkLParen {
+		p.advance()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.cur().kind != tkRParen {
+			return nil, fmt.Errorf("expected ')' in filter")
+		}
+		p.advance()
+		return inner, nil
+	}
+	if p.cur().kind == tkIdent && p.cur().s == "exists" {
+		p.advance()
+		if p.cur().kind != tkLParen {
+			return nil, fmt.Errorf("expected '(' after exists")
+		}
+		p.advance()
+		if p.cur().kind != tkField {
+			return nil, fmt.Errorf("expected field reference inside exists()")
+		}
+		ref := fieldRefNode{path: strings.Split(p.cur().s, ".")}
+		p.advance()
+		if p.cur().kind != tkRParen {
+			return nil, fmt.Errorf("expected ')' after exists(@...)")
+		}
+		p.advance()
+		return existsNode{ref: ref}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (boolNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	switch p.cur().kind {
+	case tkOp:
+		op := p.cur().s
+		p.advance()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return compareNode{op: op, left: left, right: right}, nil
+	case tkIdent:
+		switch p.cur().s {
+		case "in", "nin":
+			negated := p.cur().s == "nin"
+			p.advance()
+			list, err := p.parseList()
+			if err != nil {
+				return nil, err
+			}
+			return inNode{left: left, list: list, negated: negated}, nil
+		}
+	}
+	return nil, fmt.Errorf("expected comparison operator in filter")
+}
+
+func (p *filterParser) parseList() ([]exprNode, error) {
+	if p.cur().kind != tkLBracket {
+		return nil, fmt.Errorf("expected '[' to start list")
+	}
+	p.advance()
+	var list []exprNode
+	if p.cur().kind != tkRBracket {
+		for {
+			v, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			if p.cur().kind == tkComma {
+				p.advance()
+				continue
+			}
+			break
+		}
+	}
+	if p.cur().kind != tkRBracket {
+		return nil, fmt.Errorf("expected ']' to close list")
+	}
+	p.advance()
+	return list, nil
+}
+
+func (p *filterParser) parseOperand() (exprNode, error) {
+	t := p.cur()
+	switch t.kind {
+	case tkString:
+		p.advance()
+		return litStringNode{s: t.s}, nil
+	case tkNumber:
+		p.advance()
+		return litNumberNode{n: t.n}, nil
+	case tkField:
+		p.advance()
+		return fieldRefNode{path: strings.Split(t.s, ".")}, nil
+	default:
+		return nil, fmt.Errorf("expected value in filter")
+	}
+}
@@ -0,0 +1,78 @@
+package jsonpath
+
+import (
+	"testing"
+	"time"
+)
+
+// FuzzParsePaths feeds arbitrary strings to ParsePaths. It's seeded with
+// every path expression in the tests table (plus a handful of malformed
+// variants) and asserts only that parsing never panics and never runs away --
+// a parse error is an entirely acceptable outcome for fuzz-generated input.
+func FuzzParsePaths(f *testing.F) {
+	for _, tc := range tests {
+		f.Add(tc.path)
+	}
+	f.Add(`$..` + "\x00" + `[*]+`)
+	// An unpaired UTF-16 surrogate half, as raw WTF-8 bytes -- Go rejects
+	// "\ud83d" directly as an invalid Unicode code point in a string literal.
+	f.Add(`$.a` + "\xed\xa0\xbd" + `+`)
+	f.Add(`{a: $.a+`)
+	f.Add(`$.a[?(`)
+
+	f.Fuzz(func(t *testing.T, path string) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			// A panic here fails the fuzz test same as a returned error
+			// would not; let it propagate to the harness.
+			_, _ = ParsePaths(path)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("ParsePaths(%q) did not return within 1s", path)
+		}
+	})
+}
+
+// FuzzEvalPathsInBytes feeds arbitrary (path, json) pairs to ParsePaths +
+// EvalPathsInBytes. It's seeded from the tests table and asserts no panic,
+// no hang, and -- via the MaxDepth default baked into EvalPathsInBytes -- no
+// unbounded recursion on adversarially deep input.
+func FuzzEvalPathsInBytes(f *testing.F) {
+	for _, tc := range tests {
+		f.Add(tc.path, tc.json)
+	}
+	f.Add(`$..[*]+`, `[[[[[[[[[[]]]]]]]]]]`)
+	f.Add(`$.aKey+`, `{"aKey":`)
+	f.Add(`$.aKey+`, `{"aKey":"unterminated`)
+	f.Add(`$.aKey+`, `{"aKey":"\ud83d"}`)
+
+	f.Fuzz(func(t *testing.T, path, json string) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			paths, err := ParsePaths(path)
+			if err != nil {
+				return
+			}
+			eval, err := EvalPathsInBytes([]byte(json), paths)
+			if err != nil {
+				return
+			}
+			for {
+				r, ok := eval.Next()
+				if !ok {
+					break
+				}
+				_ = r.Slice([]byte(json))
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("EvalPathsInBytes(%q, %q) did not return within 1s", json, path)
+		}
+	})
+}
@@ -0,0 +1,211 @@
+package jsonpath
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// edit is a single byte-range replacement to apply to a document: copy
+// src[:start], write replacement, resume at src[end:]. A pure insertion sets
+// start == end.
+type edit struct {
+	start, end  int64
+	replacement []byte
+}
+
+// applyEdits rewrites src by applying every edit, in position order, in a
+// single left-to-right pass -- the same one-pass-over-the-document approach
+// EvalPathsInBytes uses to find the edits in the first place.
+func applyEdits(src []byte, edits []edit) []byte {
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		return edits[i].end < edits[j].end
+	})
+
+	var buf bytes.Buffer
+	pos := int64(0)
+	for _, e := range edits {
+		if e.start < pos {
+			// Overlaps an already-applied edit (e.g. two paths matched the
+			// same region); skip it rather than corrupt the output.
+			continue
+		}
+		buf.Write(src[pos:e.start])
+		buf.Write(e.replacement)
+		pos = e.end
+	}
+	buf.Write(src[pos:])
+	return buf.Bytes()
+}
+
+// SetPathsInBytes replaces the raw value of every match of paths in src with
+// newValue, returning a new document. newValue is written verbatim, so it
+// must already be valid JSON for the result to parse.
+func SetPathsInBytes(src []byte, paths []*Path, newValue []byte) ([]byte, error) {
+	eval, err := EvalPathsInBytes(src, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []edit
+	for {
+		r, ok := eval.Next()
+		if !ok {
+			break
+		}
+		edits = append(edits, edit{start: r.Start, end: r.End, replacement: newValue})
+	}
+	if eval.Error != nil {
+		return nil, eval.Error
+	}
+	return applyEdits(src, edits), nil
+}
+
+// SetPathsInReader is SetPathsInBytes for a document read from r.
+func SetPathsInReader(r io.Reader, paths []*Path, newValue []byte) ([]byte, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return SetPathsInBytes(src, paths, newValue)
+}
+
+// DeletePathsInBytes removes every match of paths from src -- an object
+// member (key, colon, and value) or an array element -- fixing up the
+// separating comma so the result stays valid JSON.
+func DeletePathsInBytes(src []byte, paths []*Path) ([]byte, error) {
+	eval, err := EvalPathsInBytes(src, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []edit
+	for {
+		r, ok := eval.Next()
+		if !ok {
+			break
+		}
+		start, end := deleteSpan(src, r.Start, r.End)
+		edits = append(edits, edit{start: start, end: end})
+	}
+	if eval.Error != nil {
+		return nil, eval.Error
+	}
+	return applyEdits(src, edits), nil
+}
+
+// DeletePathsInReader is DeletePathsInBytes for a document read from r.
+func DeletePathsInReader(r io.Reader, paths []*Path) ([]byte, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return DeletePathsInBytes(src, paths)
+}
+
+// deleteSpan widens [valStart, valEnd) -- the span of a matched value -- to
+// also cover its object key (if any) and exactly one of the commas
+// separating it from a sibling, so deleting it leaves valid JSON behind.
+func deleteSpan(src []byte, valStart, valEnd int64) (int64, int64) {
+	start := valStart
+	for start > 0 && isSpace(src[start-1]) {
+		start--
+	}
+	if start > 0 && src[start-1] == ':' {
+		start--
+		for start > 0 && isSpace(src[start-1]) {
+			start--
+		}
+		if start > 0 && src[start-1] == '"' {
+			start--
+			// Walk back to the key's opening quote, passing over any quote
+			// escaped by an odd run of backslashes inside the key.
+			for start > 0 {
+				if src[start-1] == '"' {
+					n := 0
+					for i := start - 2; i >= 0 && src[i] == '\\'; i-- {
+						n++
+					}
+					if n%2 == 0 {
+						break
+					}
+				}
+				start--
+			}
+			if start > 0 {
+				start--
+			}
+		}
+	}
+
+	end := valEnd
+	j := end
+	for j < int64(len(src)) && isSpace(src[j]) {
+		j++
+	}
+	if j < int64(len(src)) && src[j] == ',' {
+		return start, j + 1
+	}
+
+	k := start
+	for k > 0 && isSpace(src[k-1]) {
+		k--
+	}
+	if k > 0 && src[k-1] == ',' {
+		return k - 1, end
+	}
+
+	return start, end
+}
+
+// AppendPathsInBytes appends newValue as an additional element of every
+// array matched by paths in src, returning a new document.
+func AppendPathsInBytes(src []byte, paths []*Path, newValue []byte) ([]byte, error) {
+	eval, err := EvalPathsInBytes(src, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []edit
+	for {
+		r, ok := eval.Next()
+		if !ok {
+			break
+		}
+		if r.Type != JsonArray {
+			continue
+		}
+		pos := r.End - 1 // position of the closing ']'
+		insert := make([]byte, 0, len(newValue)+1)
+		if !arrayIsEmpty(r.Value) {
+			insert = append(insert, ',')
+		}
+		insert = append(insert, newValue...)
+		edits = append(edits, edit{start: pos, end: pos, replacement: insert})
+	}
+	if eval.Error != nil {
+		return nil, eval.Error
+	}
+	return applyEdits(src, edits), nil
+}
+
+// AppendPathsInReader is AppendPathsInBytes for a document read from r.
+func AppendPathsInReader(r io.Reader, paths []*Path, newValue []byte) ([]byte, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return AppendPathsInBytes(src, paths, newValue)
+}
+
+func arrayIsEmpty(raw []byte) bool {
+	for i := 1; i < len(raw)-1; i++ {
+		if !isSpace(raw[i]) {
+			return false
+		}
+	}
+	return true
+}
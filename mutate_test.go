@@ -0,0 +1,132 @@
+package jsonpath
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustPaths(t *testing.T, path string) []*Path {
+	paths, err := ParsePaths(path)
+	if err != nil {
+		t.Fatalf("ParsePaths(%q): %v", path, err)
+	}
+	return paths
+}
+
+func TestSetPathsInBytes(t *testing.T) {
+	as := assert.New(t)
+
+	out, err := SetPathsInBytes([]byte(`{"aKey":{"bKey":1}}`), mustPaths(t, `$.aKey.bKey+`), []byte(`2`))
+	as.NoError(err)
+	as.JSONEq(`{"aKey":{"bKey":2}}`, string(out))
+
+	out, err = SetPathsInBytes([]byte(`{"items":[1,2,3]}`), mustPaths(t, `$.items[*]+`), []byte(`0`))
+	as.NoError(err)
+	as.JSONEq(`{"items":[0,0,0]}`, string(out))
+
+	// Round-trip: the mutated document should still be queryable and yield
+	// the newly-set value.
+	paths := mustPaths(t, `$.items[*]+`)
+	eval, err := EvalPathsInBytes(out, paths)
+	as.NoError(err)
+	as.Equal(`0`, string(mustNext(t, eval).Value))
+}
+
+func TestSetPathsInReader(t *testing.T) {
+	as := assert.New(t)
+
+	out, err := SetPathsInReader(strings.NewReader(`{"aKey":{"bKey":1}}`), mustPaths(t, `$.aKey.bKey+`), []byte(`2`))
+	as.NoError(err)
+	as.JSONEq(`{"aKey":{"bKey":2}}`, string(out))
+}
+
+func TestDeletePathsInBytes(t *testing.T) {
+	as := assert.New(t)
+
+	// Object member at head, middle, and tail.
+	out, err := DeletePathsInBytes([]byte(`{"a":1,"b":2,"c":3}`), mustPaths(t, `$.a+`))
+	as.NoError(err)
+	as.JSONEq(`{"b":2,"c":3}`, string(out))
+
+	out, err = DeletePathsInBytes([]byte(`{"a":1,"b":2,"c":3}`), mustPaths(t, `$.b+`))
+	as.NoError(err)
+	as.JSONEq(`{"a":1,"c":3}`, string(out))
+
+	out, err = DeletePathsInBytes([]byte(`{"a":1,"b":2,"c":3}`), mustPaths(t, `$.c+`))
+	as.NoError(err)
+	as.JSONEq(`{"a":1,"b":2}`, string(out))
+
+	out, err = DeletePathsInBytes([]byte(`{"a":1}`), mustPaths(t, `$.a+`))
+	as.NoError(err)
+	as.JSONEq(`{}`, string(out))
+
+	// Array elements at head, middle, and tail.
+	out, err = DeletePathsInBytes([]byte(`{"items":[1,2,3]}`), mustPaths(t, `$.items[0]+`))
+	as.NoError(err)
+	as.JSONEq(`{"items":[2,3]}`, string(out))
+
+	out, err = DeletePathsInBytes([]byte(`{"items":[1,2,3]}`), mustPaths(t, `$.items[1]+`))
+	as.NoError(err)
+	as.JSONEq(`{"items":[1,3]}`, string(out))
+
+	out, err = DeletePathsInBytes([]byte(`{"items":[1,2,3]}`), mustPaths(t, `$.items[2]+`))
+	as.NoError(err)
+	as.JSONEq(`{"items":[1,2]}`, string(out))
+
+	// Nested path, wildcard multi-match deletion.
+	out, err = DeletePathsInBytes([]byte(`{"items":[{"a":1,"b":2},{"a":3,"b":4}]}`), mustPaths(t, `$.items[*].b+`))
+	as.NoError(err)
+	as.JSONEq(`{"items":[{"a":1},{"a":3}]}`, string(out))
+
+	// Round-trip: the result should still parse and no longer contain the
+	// deleted key.
+	paths := mustPaths(t, `$.a+`)
+	eval, err := EvalPathsInBytes([]byte(out), paths)
+	as.NoError(err)
+	_, ok := eval.Next()
+	as.False(ok)
+}
+
+func TestDeletePathsInReader(t *testing.T) {
+	as := assert.New(t)
+
+	out, err := DeletePathsInReader(strings.NewReader(`{"a":1,"b":2,"c":3}`), mustPaths(t, `$.a+`))
+	as.NoError(err)
+	as.JSONEq(`{"b":2,"c":3}`, string(out))
+}
+
+func TestAppendPathsInBytes(t *testing.T) {
+	as := assert.New(t)
+
+	out, err := AppendPathsInBytes([]byte(`{"items":[1,2]}`), mustPaths(t, `$.items+`), []byte(`3`))
+	as.NoError(err)
+	as.JSONEq(`{"items":[1,2,3]}`, string(out))
+
+	out, err = AppendPathsInBytes([]byte(`{"items":[]}`), mustPaths(t, `$.items+`), []byte(`1`))
+	as.NoError(err)
+	as.JSONEq(`{"items":[1]}`, string(out))
+
+	// Round-trip: the appended element should be queryable afterward.
+	paths := mustPaths(t, `$.items[*]+`)
+	eval, err := EvalPathsInBytes(out, paths)
+	as.NoError(err)
+	as.Equal(`1`, string(mustNext(t, eval).Value))
+}
+
+func TestAppendPathsInReader(t *testing.T) {
+	as := assert.New(t)
+
+	out, err := AppendPathsInReader(strings.NewReader(`{"items":[1,2]}`), mustPaths(t, `$.items+`), []byte(`3`))
+	as.NoError(err)
+	as.JSONEq(`{"items":[1,2,3]}`, string(out))
+}
+
+func mustNext(t *testing.T, e *Eval) *Result {
+	r, ok := e.Next()
+	if !ok {
+		t.Fatalf("expected a match, got none")
+	}
+	return r
+}
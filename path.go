@@ -0,0 +1,385 @@
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type segKind int
+
+const (
+	segKey segKind = iota
+	segWildcard
+	segIndex
+	segSlice
+	segFilter
+	segDescent
+)
+
+type segment struct {
+	kind        segKind
+	key         string
+	index       int
+	sliceStart  int
+	sliceEnd    int
+	sliceHasEnd bool
+	filter      *filterExpr
+}
+
+// Path is a single compiled JSONPath expression, as produced by ParsePaths.
+// A Path is either an ordinary segment chain (segments is non-empty or the
+// expression is `$+`) or a projection (projection is non-nil), never both.
+type Path struct {
+	raw      string
+	segments []segment
+	op       byte
+
+	projection *projection
+}
+
+type projKind int
+
+const (
+	projObject projKind = iota
+	projArray
+)
+
+// projection is a multipath / composite-value expression, such as
+// `{name: $.items[*].name, value: $.items[*].value}+` or `[$.a, $.b]+`. Each
+// field's sub-path is evaluated independently against the document; the
+// results are assembled into one freshly-synthesized JSON object or array
+// per call, rather than sliced from the input.
+type projection struct {
+	kind   projKind
+	fields []projField
+}
+
+// projField is one member of a projection: key is empty for array
+// projections, where members are positional.
+type projField struct {
+	key  string
+	path *Path
+}
+
+func isIdentByte(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || b == '_'
+}
+
+// ParsePaths parses a JSONPath expression, such as `$.aKey[0]+`, into a Path
+// that EvalPathsInBytes / EvalPathsInReader can evaluate against a document.
+// Multiple path expressions may be supplied separated by commas; each is
+// compiled and matched independently against the same document in a single
+// pass.
+func ParsePaths(s string) ([]*Path, error) {
+	parts := splitTopLevel(s, ',')
+	paths := make([]*Path, 0, len(parts))
+	for _, part := range parts {
+		p, err := parsePath(part)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+// splitTopLevel splits s on sep, ignoring occurrences of sep that are nested
+// inside (), [], {}, or string literals.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth := 0
+	inStr := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inStr {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inStr = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inStr = true
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+	return parts
+}
+
+func parsePath(s string) (*Path, error) {
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return parseProjection(trimmed)
+	}
+
+	orig := s
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("jsonpath: path must start with '$': %q", orig)
+	}
+	segs, rest, err := parseSegments(s[1:], orig)
+	if err != nil {
+		return nil, err
+	}
+	if rest == "" {
+		return nil, fmt.Errorf("jsonpath: path %q is missing a trailing operator", orig)
+	}
+	op, err := parseOp(rest, orig)
+	if err != nil {
+		return nil, err
+	}
+	return &Path{raw: orig, segments: segs, op: op}, nil
+}
+
+// parseSubPath parses one projection field's sub-path, such as
+// `$.items[*].name` -- the same segment grammar as a top-level Path, but
+// without a trailing operator, since it's the projection as a whole (not
+// each field) that carries the `+`. A sub-path that is itself a nested
+// projection (`{b: $.x}+`) is the one exception: it's delegated to
+// parseProjection, which -- same as a top-level projection -- requires its
+// own trailing `+`.
+func parseSubPath(s string) (*Path, error) {
+	trimmed := strings.TrimSpace(s)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return parseProjection(trimmed)
+	}
+
+	orig := s
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("jsonpath: path must start with '$': %q", orig)
+	}
+	segs, rest, err := parseSegments(s[1:], orig)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("jsonpath: unexpected trailing %q in sub-path %q", rest, orig)
+	}
+	return &Path{raw: orig, segments: segs}, nil
+}
+
+// parseSegments parses the `.key` / `.*` / `..` / `[...]` / `?(...)` segment
+// chain following the leading `$`, stopping at (and returning, unconsumed)
+// whatever comes after the last recognized segment -- a trailing operator for
+// a top-level Path, or nothing at all for a projection sub-path.
+func parseSegments(s, orig string) (segs []segment, rest string, err error) {
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			if len(s) == 0 {
+				return nil, "", fmt.Errorf("jsonpath: trailing '.' in %q", orig)
+			}
+			if s[0] == '.' {
+				// Recursive descent: `..key`, `..*`, or `..[...]` all apply
+				// the following selector at every depth, not just the next
+				// one, so the descent segment itself consumes no key name.
+				segs = append(segs, segment{kind: segDescent})
+				s = s[1:]
+				if len(s) > 0 && isIdentByte(s[0]) {
+					i := 0
+					for i < len(s) && isIdentByte(s[i]) {
+						i++
+					}
+					segs = append(segs, segment{kind: segKey, key: s[:i]})
+					s = s[i:]
+				} else if len(s) > 0 && s[0] == '*' {
+					segs = append(segs, segment{kind: segWildcard})
+					s = s[1:]
+				}
+				continue
+			}
+			if s[0] == '*' {
+				segs = append(segs, segment{kind: segWildcard})
+				s = s[1:]
+				continue
+			}
+			i := 0
+			for i < len(s) && isIdentByte(s[i]) {
+				i++
+			}
+			if i == 0 {
+				return nil, "", fmt.Errorf("jsonpath: expected key name in %q", orig)
+			}
+			segs = append(segs, segment{kind: segKey, key: s[:i]})
+			s = s[i:]
+		case '[':
+			seg, bracketRest, err := parseBracket(s, orig)
+			if err != nil {
+				return nil, "", err
+			}
+			segs = append(segs, seg)
+			s = bracketRest
+		case '?':
+			// A bare `?(...)` filter guard directly following a segment
+			// (`$.items[*]?(@.name == "bravo")`), as opposed to one wrapped
+			// in its own `[...]`.
+			if len(s) < 2 || s[1] != '(' {
+				return nil, "", fmt.Errorf("jsonpath: expected '(' after '?' in %q", orig)
+			}
+			end := matchingBracket(s, 1)
+			if end < 0 {
+				return nil, "", fmt.Errorf("jsonpath: unterminated '?(' in %q", orig)
+			}
+			expr, err := parseFilterExpr(s[2:end])
+			if err != nil {
+				return nil, "", fmt.Errorf("jsonpath: %v in %q", err, orig)
+			}
+			segs = append(segs, segment{kind: segFilter, filter: expr})
+			s = s[end+1:]
+		default:
+			return segs, s, nil
+		}
+	}
+	return segs, "", nil
+}
+
+// parseProjection parses a `{key: path, ...}` or `[path, ...]` multipath
+// expression into a Path whose projection field is populated.
+func parseProjection(s string) (*Path, error) {
+	orig := s
+	open := s[0]
+	kind := projObject
+	if open == '[' {
+		kind = projArray
+	}
+
+	end := matchingBracket(s, 0)
+	if end < 0 {
+		return nil, fmt.Errorf("jsonpath: unterminated %q in %q", string(open), orig)
+	}
+
+	op, err := parseOp(s[end+1:], orig)
+	if err != nil {
+		return nil, err
+	}
+
+	inner := strings.TrimSpace(s[1:end])
+	var fields []projField
+	if inner != "" {
+		for _, part := range splitTopLevel(inner, ',') {
+			part = strings.TrimSpace(part)
+			if kind == projArray {
+				sub, err := parseSubPath(part)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, projField{path: sub})
+				continue
+			}
+			kv := splitTopLevel(part, ':')
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("jsonpath: expected \"key: path\" in %q", orig)
+			}
+			key := strings.Trim(strings.TrimSpace(kv[0]), `"`)
+			sub, err := parseSubPath(strings.TrimSpace(kv[1]))
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, projField{key: key, path: sub})
+		}
+	}
+
+	return &Path{raw: orig, op: op, projection: &projection{kind: kind, fields: fields}}, nil
+}
+
+func parseOp(s, orig string) (byte, error) {
+	if s != "+" {
+		return 0, fmt.Errorf("jsonpath: unsupported operator %q in %q", s, orig)
+	}
+	return s[0], nil
+}
+
+func parseBracket(s, orig string) (segment, string, error) {
+	if s[0] != '[' {
+		return segment{}, s, fmt.Errorf("jsonpath: expected '[' in %q", orig)
+	}
+	end := matchingBracket(s, 0)
+	if end < 0 {
+		return segment{}, s, fmt.Errorf("jsonpath: unterminated '[' in %q", orig)
+	}
+	inner := s[1:end]
+	rest := s[end+1:]
+
+	switch {
+	case inner == "*":
+		return segment{kind: segWildcard}, rest, nil
+	case strings.HasPrefix(inner, "?("):
+		if !strings.HasSuffix(inner, ")") {
+			return segment{}, s, fmt.Errorf("jsonpath: malformed filter in %q", orig)
+		}
+		expr, err := parseFilterExpr(inner[2 : len(inner)-1])
+		if err != nil {
+			return segment{}, s, fmt.Errorf("jsonpath: %v in %q", err, orig)
+		}
+		return segment{kind: segFilter, filter: expr}, rest, nil
+	case strings.Contains(inner, ":"):
+		parts := strings.SplitN(inner, ":", 2)
+		seg := segment{kind: segSlice}
+		if parts[0] != "" {
+			n, err := strconv.Atoi(parts[0])
+			if err != nil {
+				return segment{}, s, fmt.Errorf("jsonpath: bad slice start in %q", orig)
+			}
+			seg.sliceStart = n
+		}
+		if parts[1] != "" {
+			n, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return segment{}, s, fmt.Errorf("jsonpath: bad slice end in %q", orig)
+			}
+			seg.sliceEnd = n
+			seg.sliceHasEnd = true
+		}
+		return seg, rest, nil
+	default:
+		n, err := strconv.Atoi(inner)
+		if err != nil {
+			return segment{}, s, fmt.Errorf("jsonpath: bad index %q in %q", inner, orig)
+		}
+		return segment{kind: segIndex, index: n}, rest, nil
+	}
+}
+
+// matchingBracket returns the index of the '}'/']'/')' matching the
+// opening bracket at s[open], respecting nesting of all three kinds and
+// string literals.
+func matchingBracket(s string, open int) int {
+	depth := 0
+	inStr := false
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		if inStr {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inStr = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inStr = true
+		case '[', '(', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
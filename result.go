@@ -0,0 +1,60 @@
+package jsonpath
+
+// Value type tags for a matched Result.
+const (
+	JsonNull = iota
+	JsonString
+	JsonNumber
+	JsonBool
+	JsonObject
+	JsonArray
+)
+
+// Result is a single match produced by evaluating a Path against a JSON
+// document. Value holds the raw (still JSON-encoded) bytes of the match,
+// exactly as they appear in the source document. The one exception is a
+// projection's Result, whose Value is a freshly-assembled JSON object or
+// array rather than a slice of the source.
+type Result struct {
+	PathString string
+	Value      []byte
+	Keys       []interface{}
+	Type       int
+
+	// Start and End are the byte offsets into the original input at which
+	// the raw value begins and ends, i.e. src[Start:End] == Value. Both are
+	// zero for a projection's Result.
+	Start int64
+	End   int64
+}
+
+// Slice returns the raw bytes of this match from src, equivalent to
+// src[r.Start:r.End]. It's a convenience for callers extracting or patching
+// the original document without re-deriving the offsets themselves.
+func (r Result) Slice(src []byte) []byte {
+	return src[r.Start:r.End]
+}
+
+// Eval is the handle returned by EvalPathsInBytes / EvalPathsInReader. Callers
+// pull matches one at a time via Next() until it returns ok == false, at
+// which point Error (if any) should be checked.
+type Eval struct {
+	Error   error
+	results []*Result
+	pos     int
+}
+
+// Next returns the next match, if any. ok is false once every match has been
+// returned; callers should then inspect Error.
+func (e *Eval) Next() (*Result, bool) {
+	if e.pos >= len(e.results) {
+		return nil, false
+	}
+	r := e.results[e.pos]
+	e.pos++
+	return r, true
+}
+
+func newEval(results []*Result, err error) *Eval {
+	return &Eval{Error: err, results: results}
+}
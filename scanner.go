@@ -0,0 +1,339 @@
+package jsonpath
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// byteSource abstracts over the two places a JSON document can come from
+// ([]byte or io.Reader) so the tokenizer only has to be written once. It also
+// tracks the running byte offset of the cursor so matches can report where in
+// the original input they were found.
+type byteSource interface {
+	// next consumes and returns the next byte. ok is false at EOF.
+	next() (byte, bool)
+	// peek returns the next byte without consuming it. ok is false at EOF.
+	peek() (byte, bool)
+	// offset returns the offset (in bytes) of the next unread byte.
+	offset() int64
+}
+
+type bytesSource struct {
+	buf []byte
+	pos int64
+}
+
+func newBytesSource(buf []byte) *bytesSource {
+	return &bytesSource{buf: buf}
+}
+
+func (s *bytesSource) next() (byte, bool) {
+	if int64(len(s.buf)) <= s.pos {
+		return 0, false
+	}
+	b := s.buf[s.pos]
+	s.pos++
+	return b, true
+}
+
+func (s *bytesSource) peek() (byte, bool) {
+	if int64(len(s.buf)) <= s.pos {
+		return 0, false
+	}
+	return s.buf[s.pos], true
+}
+
+func (s *bytesSource) offset() int64 {
+	return s.pos
+}
+
+type readerSource struct {
+	r       *bufio.Reader
+	pos     int64
+	peeked  bool
+	peekVal byte
+}
+
+func newReaderSource(r io.Reader) *readerSource {
+	return &readerSource{r: bufio.NewReader(r)}
+}
+
+func (s *readerSource) next() (byte, bool) {
+	if s.peeked {
+		s.peeked = false
+		s.pos++
+		return s.peekVal, true
+	}
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return 0, false
+	}
+	s.pos++
+	return b, true
+}
+
+func (s *readerSource) peek() (byte, bool) {
+	if s.peeked {
+		return s.peekVal, true
+	}
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return 0, false
+	}
+	s.peeked = true
+	s.peekVal = b
+	return b, true
+}
+
+func (s *readerSource) offset() int64 {
+	return s.pos
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+func skipSpace(src byteSource) {
+	for {
+		b, ok := src.peek()
+		if !ok || !isSpace(b) {
+			return
+		}
+		src.next()
+	}
+}
+
+// readString consumes a JSON string literal (including the surrounding
+// quotes) starting at the current position, which must be a `"`. It returns
+// the raw bytes of the literal (quotes included) and the unescaped content.
+func readString(src byteSource) (raw []byte, content []byte, err error) {
+	b, ok := src.next()
+	if !ok || b != '"' {
+		return nil, nil, fmt.Errorf("jsonpath: expected '\"' at offset %d", src.offset())
+	}
+	raw = append(raw, '"')
+	for {
+		b, ok := src.next()
+		if !ok {
+			return nil, nil, fmt.Errorf("jsonpath: unterminated string")
+		}
+		raw = append(raw, b)
+		if b == '\\' {
+			esc, ok := src.next()
+			if !ok {
+				return nil, nil, fmt.Errorf("jsonpath: unterminated escape")
+			}
+			raw = append(raw, esc)
+			if esc != 'u' {
+				content = append(content, unescapeByte(esc))
+				continue
+			}
+			r, hex, err := readHex4(src)
+			raw = append(raw, hex...)
+			if err != nil {
+				return nil, nil, err
+			}
+			if utf16.IsSurrogate(r) {
+				// A high surrogate half only makes sense immediately
+				// followed by its low half (e.g. D83D then DE00 for U+1F600), JSON's way
+				// of encoding a character outside the BMP as a UTF-16 pair; a
+				// literal '\' can't otherwise appear unescaped in a string,
+				// so seeing one here always means another escape follows.
+				if nb, ok := src.peek(); ok && nb == '\\' {
+					src.next()
+					raw = append(raw, nb)
+					esc2, ok := src.next()
+					if !ok {
+						return nil, nil, fmt.Errorf("jsonpath: unterminated escape")
+					}
+					raw = append(raw, esc2)
+					if esc2 != 'u' {
+						content = utf8.AppendRune(content, utf8.RuneError)
+						content = append(content, unescapeByte(esc2))
+						continue
+					}
+					r2, hex2, err := readHex4(src)
+					raw = append(raw, hex2...)
+					if err != nil {
+						return nil, nil, err
+					}
+					if combined := utf16.DecodeRune(r, r2); combined != utf8.RuneError {
+						content = utf8.AppendRune(content, combined)
+						continue
+					}
+					content = utf8.AppendRune(content, r)
+					content = utf8.AppendRune(content, r2)
+					continue
+				}
+			}
+			content = utf8.AppendRune(content, r)
+			continue
+		}
+		if b == '"' {
+			break
+		}
+		content = append(content, b)
+	}
+	return raw, content, nil
+}
+
+func unescapeByte(esc byte) byte {
+	switch esc {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	default:
+		return esc
+	}
+}
+
+// readHex4 reads the 4 hex digits following a `\u` escape, returning the
+// decoded UTF-16 code unit (which may be one half of a surrogate pair, not a
+// complete rune on its own -- see the pairing logic in readString) along with
+// the raw digit bytes consumed.
+func readHex4(src byteSource) (rune, []byte, error) {
+	hex := make([]byte, 0, 4)
+	var v rune
+	for i := 0; i < 4; i++ {
+		b, ok := src.next()
+		if !ok {
+			return 0, hex, fmt.Errorf("jsonpath: unterminated \\u escape")
+		}
+		hex = append(hex, b)
+		d, ok := hexDigitValue(b)
+		if !ok {
+			return 0, hex, fmt.Errorf("jsonpath: invalid \\u escape %q", hex)
+		}
+		v = v<<4 | d
+	}
+	return v, hex, nil
+}
+
+func hexDigitValue(b byte) (rune, bool) {
+	switch {
+	case b >= '0' && b <= '9':
+		return rune(b - '0'), true
+	case b >= 'a' && b <= 'f':
+		return rune(b-'a') + 10, true
+	case b >= 'A' && b <= 'F':
+		return rune(b-'A') + 10, true
+	default:
+		return 0, false
+	}
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// readLiteralBytes consumes a bare JSON literal (number, true, false, null)
+// up to the next structural character or whitespace, returning the raw bytes
+// consumed.
+func readLiteralBytes(src byteSource) []byte {
+	var raw []byte
+	for {
+		b, ok := src.peek()
+		if !ok {
+			break
+		}
+		if isSpace(b) || b == ',' || b == '}' || b == ']' {
+			break
+		}
+		src.next()
+		raw = append(raw, b)
+	}
+	return raw
+}
+
+// skipValue consumes one full JSON value (object, array, string, or bare
+// literal) starting at the current position, returning its raw bytes and
+// type.
+func skipValue(src byteSource) (raw []byte, typ int, err error) {
+	skipSpace(src)
+	b, ok := src.peek()
+	if !ok {
+		return nil, JsonNull, fmt.Errorf("jsonpath: unexpected end of input")
+	}
+	switch {
+	case b == '"':
+		s, _, err := readString(src)
+		return s, JsonString, err
+	case b == '{':
+		return skipContainer(src, '{', '}')
+	case b == '[':
+		return skipContainer(src, '[', ']')
+	case b == 't' || b == 'f':
+		lit := readLiteralBytes(src)
+		return lit, JsonBool, nil
+	case b == 'n':
+		lit := readLiteralBytes(src)
+		return lit, JsonNull, nil
+	case b == '-' || isDigit(b):
+		lit := readLiteralBytes(src)
+		return lit, JsonNumber, nil
+	default:
+		return nil, JsonNull, fmt.Errorf("jsonpath: unexpected character %q at offset %d", b, src.offset())
+	}
+}
+
+// skipContainer consumes a balanced {...} or [...], respecting strings, and
+// returns the raw bytes of the whole container.
+func skipContainer(src byteSource, open, close byte) ([]byte, int, error) {
+	var raw []byte
+	b, ok := src.next()
+	if !ok || b != open {
+		return nil, JsonNull, fmt.Errorf("jsonpath: expected %q", open)
+	}
+	raw = append(raw, b)
+	depth := 1
+	for depth > 0 {
+		b, ok := src.next()
+		if !ok {
+			return nil, JsonNull, fmt.Errorf("jsonpath: unterminated container")
+		}
+		if b == '"' {
+			raw = append(raw, b)
+			for {
+				c, ok := src.next()
+				if !ok {
+					return nil, JsonNull, fmt.Errorf("jsonpath: unterminated string")
+				}
+				raw = append(raw, c)
+				if c == '\\' {
+					e, ok := src.next()
+					if !ok {
+						return nil, JsonNull, fmt.Errorf("jsonpath: unterminated escape")
+					}
+					raw = append(raw, e)
+					continue
+				}
+				if c == '"' {
+					break
+				}
+			}
+			continue
+		}
+		raw = append(raw, b)
+		switch b {
+		case open:
+			depth++
+		case close:
+			depth--
+		}
+	}
+	typ := JsonObject
+	if open == '[' {
+		typ = JsonArray
+	}
+	return raw, typ, nil
+}